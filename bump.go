@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/go-version"
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	BumpMajor = "major"
+	BumpMinor = "minor"
+	BumpPatch = "patch"
+	BumpAuto  = "auto"
+)
+
+var conventionalCommitExpr = regexp.MustCompile(`^([a-zA-Z]+)(\([^)]*\))?(!)?:\s*(.*)$`)
+
+// commitsSinceTag returns the commits reachable from ref that are not reachable
+// from tag, i.e. the commits that would be included in the next tag.
+func commitsSinceTag(ctx context.Context, api *gitlab.Client, project *gitlab.Project, tag string, ref string) ([]*gitlab.Commit, error) {
+	compare, _, err := api.Repositories.Compare(project.ID, &gitlab.CompareOptions{
+		From: gitlab.String(tag),
+		To:   gitlab.String(ref),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	return compare.Commits, nil
+}
+
+// inferBump inspects a range of commits and returns the highest-precedence
+// Conventional Commits bump found, defaulting to BumpPatch when nothing
+// stronger is detected.
+func inferBump(commits []*gitlab.Commit) string {
+	bump := BumpPatch
+
+	for _, commit := range commits {
+		switch commitBump(commit) {
+		case BumpMajor:
+			return BumpMajor
+		case BumpMinor:
+			bump = BumpMinor
+		}
+	}
+
+	return bump
+}
+
+func commitBump(commit *gitlab.Commit) string {
+	if strings.Contains(commit.Message, "BREAKING CHANGE:") {
+		return BumpMajor
+	}
+
+	subject := strings.SplitN(commit.Title, "\n", 2)[0]
+	match := conventionalCommitExpr.FindStringSubmatch(subject)
+	if match == nil {
+		return BumpPatch
+	}
+
+	if match[3] == "!" {
+		return BumpMajor
+	}
+
+	if match[1] == "feat" {
+		return BumpMinor
+	}
+
+	return BumpPatch
+}
+
+// bumpSegments applies bump to ver, resetting the lower-precedence segments,
+// and carries over any pre-release/build metadata from the current tag.
+func bumpSegments(ver *version.Version, bump string) (major, minor, patch int, pre, meta string) {
+	segs := ver.Segments()
+	major, minor, patch = segs[0], segs[1], segs[2]
+
+	switch bump {
+	case BumpMajor:
+		major, minor, patch = major+1, 0, 0
+	case BumpMinor:
+		minor, patch = minor+1, 0
+	default:
+		patch++
+	}
+
+	return major, minor, patch, ver.Prerelease(), ver.Metadata()
+}
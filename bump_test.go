@@ -0,0 +1,179 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/hashicorp/go-version"
+	"github.com/xanzy/go-gitlab"
+)
+
+func commit(title string, message string) *gitlab.Commit {
+	if message == "" {
+		message = title
+	}
+
+	return &gitlab.Commit{Title: title, Message: message}
+}
+
+func TestInferBumpMixedMessageRanges(t *testing.T) {
+	cases := []struct {
+		name     string
+		commits  []*gitlab.Commit
+		expected string
+	}{
+		{
+			name: "fix and feat mix to minor",
+			commits: []*gitlab.Commit{
+				commit("fix: patch a bug", ""),
+				commit("feat: add a widget", ""),
+			},
+			expected: BumpMinor,
+		},
+		{
+			name: "a BREAKING CHANGE footer anywhere in the range mixes to major",
+			commits: []*gitlab.Commit{
+				commit("feat: add a widget", ""),
+				commit("fix: patch a bug", "fix: patch a bug\n\nBREAKING CHANGE: drops old API"),
+			},
+			expected: BumpMajor,
+		},
+		{
+			name: "a bang breaking change mixes to major even before a feat",
+			commits: []*gitlab.Commit{
+				commit("fix!: drop deprecated flag", ""),
+				commit("feat: add a widget", ""),
+			},
+			expected: BumpMajor,
+		},
+		{
+			name: "chores and unconventional subjects default to patch",
+			commits: []*gitlab.Commit{
+				commit("chore: bump deps", ""),
+				commit("tidy up whitespace", ""),
+			},
+			expected: BumpPatch,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if got := inferBump(c.commits); got != c.expected {
+				t.Errorf("inferBump() = %q, want %q", got, c.expected)
+			}
+		})
+	}
+}
+
+func TestInferBumpNoCommitRangeDefaultsToPatch(t *testing.T) {
+	// inferBump has no opinion on an empty range; projectTagging is the one
+	// that turns an empty range into a skip, via autoBumpRangeEmpty below.
+	if got := inferBump(nil); got != BumpPatch {
+		t.Errorf("inferBump(nil) = %q, want %q", got, BumpPatch)
+	}
+}
+
+func TestAutoBumpRangeEmptySkipsProjectTagging(t *testing.T) {
+	if !autoBumpRangeEmpty(nil) {
+		t.Error("autoBumpRangeEmpty(nil) = false, want true")
+	}
+	if autoBumpRangeEmpty([]*gitlab.Commit{commit("fix: patch a bug", "")}) {
+		t.Error("autoBumpRangeEmpty(non-empty) = true, want false")
+	}
+}
+
+func TestBranchUnchangedSinceTagSkipsProjectTagging(t *testing.T) {
+	latest := &gitlab.Tag{Name: "v1.0.0", Commit: &gitlab.Commit{ID: "abc123"}}
+
+	if !branchUnchangedSinceTag(&gitlab.Commit{ID: "abc123"}, latest) {
+		t.Error("branchUnchangedSinceTag() = false, want true when the tip commit matches the tag")
+	}
+	if branchUnchangedSinceTag(&gitlab.Commit{ID: "def456"}, latest) {
+		t.Error("branchUnchangedSinceTag() = true, want false when the tip commit has moved on")
+	}
+}
+
+func TestSkipAtTagMarksResultSkipped(t *testing.T) {
+	result := skipAtTag(Result{Project: "group/project"}, "v1.0.0")
+
+	if result.Status != StatusSkipped {
+		t.Errorf("Status = %q, want %q", result.Status, StatusSkipped)
+	}
+	if result.LastTag != "v1.0.0" {
+		t.Errorf("LastTag = %q, want %q", result.LastTag, "v1.0.0")
+	}
+}
+
+func TestBumpSegments(t *testing.T) {
+	ver, err := version.NewVersion("v1.2.3")
+	if err != nil {
+		t.Fatalf("NewVersion() error = %v", err)
+	}
+
+	cases := []struct {
+		bump                string
+		major, minor, patch int
+	}{
+		{BumpPatch, 1, 2, 4},
+		{BumpMinor, 1, 3, 0},
+		{BumpMajor, 2, 0, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.bump, func(t *testing.T) {
+			major, minor, patch, _, _ := bumpSegments(ver, c.bump)
+			if major != c.major || minor != c.minor || patch != c.patch {
+				t.Errorf("bumpSegments(%s) = %d.%d.%d, want %d.%d.%d", c.bump, major, minor, patch, c.major, c.minor, c.patch)
+			}
+		})
+	}
+}
+
+func TestBumpSegmentsPreservesPrereleaseAndMetadata(t *testing.T) {
+	ver, err := version.NewVersion("v1.2.3-rc.1+build.5")
+	if err != nil {
+		t.Fatalf("NewVersion() error = %v", err)
+	}
+
+	_, _, _, pre, meta := bumpSegments(ver, BumpPatch)
+	if pre != "rc.1" {
+		t.Errorf("Prerelease() = %q, want %q", pre, "rc.1")
+	}
+	if meta != "build.5" {
+		t.Errorf("Metadata() = %q, want %q", meta, "build.5")
+	}
+}
+
+func TestParseLatestVersionFailsProjectTaggingOnInvalidTagNames(t *testing.T) {
+	for _, name := range []string{"", "not-a-version", "latest"} {
+		result := Result{Project: "group/project", LastTag: name}
+
+		ver, failed, ok := parseLatestVersion(result, name)
+		if ok {
+			t.Errorf("parseLatestVersion(%q) ok = true, want false", name)
+		}
+		if ver != nil {
+			t.Errorf("parseLatestVersion(%q) version = %v, want nil", name, ver)
+		}
+		if failed.Status != StatusFailed {
+			t.Errorf("parseLatestVersion(%q) Status = %q, want %q", name, failed.Status, StatusFailed)
+		}
+		if failed.Err == "" {
+			t.Errorf("parseLatestVersion(%q) Err is empty, want the parse error", name)
+		}
+	}
+}
+
+func TestParseLatestVersionPassesThroughValidTagNames(t *testing.T) {
+	result := Result{Project: "group/project", LastTag: "v1.2.3"}
+
+	ver, passed, ok := parseLatestVersion(result, "v1.2.3")
+	if !ok {
+		t.Fatalf("parseLatestVersion(%q) ok = false, want true", "v1.2.3")
+	}
+	if ver == nil {
+		t.Fatal("parseLatestVersion() version = nil, want a parsed version")
+	}
+	if passed.Status == StatusFailed {
+		t.Errorf("parseLatestVersion() Status = %q, want unchanged", passed.Status)
+	}
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// TargetConfig is one entry of a --config YAML file, either the shared
+// "defaults" block or a single item of "targets". Pointer fields are left
+// nil when absent from the YAML so overrides only touch what was set.
+type TargetConfig struct {
+	Project string   `yaml:"project,omitempty"`
+	Group   string   `yaml:"group,omitempty"`
+	Search  string   `yaml:"search,omitempty"`
+	Include []string `yaml:"include,omitempty"`
+	Exclude []string `yaml:"exclude,omitempty"`
+
+	Forced           *bool   `yaml:"forced,omitempty"`
+	Expired          *string `yaml:"expired,omitempty"`
+	Bump             *string `yaml:"bump,omitempty"`
+	TagTemplate      *string `yaml:"tag-template,omitempty"`
+	TagPrefix        *string `yaml:"tag-prefix,omitempty"`
+	ProtectedPattern *string `yaml:"protected-pattern,omitempty"`
+	Branch           *string `yaml:"branch,omitempty"`
+
+	ReleaseNotes         *string `yaml:"release-notes,omitempty"`
+	ReleaseNotesTemplate *string `yaml:"release-notes-template,omitempty"`
+}
+
+// Config is the document read from --config: a shared default target
+// overlaid with the CLI flags, plus one or more per-project targets.
+// Concurrency is a pointer so an absent "concurrency" key falls back to
+// the --concurrency flag instead of silently resetting it.
+type Config struct {
+	Concurrency *int           `yaml:"concurrency,omitempty"`
+	Defaults    TargetConfig   `yaml:"defaults,omitempty"`
+	Targets     []TargetConfig `yaml:"targets"`
+}
+
+// loadConfig reads and parses a --config YAML file.
+func loadConfig(path string) (*Config, error) {
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &Config{}
+	if err := yaml.Unmarshal(raw, config); err != nil {
+		return nil, err
+	}
+
+	return config, nil
+}
+
+// resolveConcurrency returns the config's concurrency override if set,
+// otherwise the --concurrency flag value.
+func resolveConcurrency(config *Config, flagConcurrency int) int {
+	if config.Concurrency != nil {
+		return *config.Concurrency
+	}
+
+	return flagConcurrency
+}
+
+// jobsFromConfig turns a Config into the Jobs it describes: the CLI flags
+// are the base, config.Defaults overrides them, and each target overrides
+// that merged default in turn.
+func jobsFromConfig(config *Config) []Job {
+	base := jobFromOptions()
+	base = applyTargetConfig(base, config.Defaults)
+
+	jobs := make([]Job, 0, len(config.Targets))
+	for _, target := range config.Targets {
+		jobs = append(jobs, applyTargetConfig(base, target))
+	}
+
+	return jobs
+}
+
+func applyTargetConfig(job Job, override TargetConfig) Job {
+	if override.Project != "" {
+		job.Project = override.Project
+	}
+	if override.Group != "" {
+		job.Group = override.Group
+	}
+	if override.Search != "" {
+		job.Search = override.Search
+	}
+	if override.Include != nil {
+		job.Include = override.Include
+	}
+	if override.Exclude != nil {
+		job.Exclude = override.Exclude
+	}
+	if override.Forced != nil {
+		job.Forced = *override.Forced
+	}
+	if override.Expired != nil {
+		job.Expired = *override.Expired
+	}
+	if override.Bump != nil {
+		job.Bump = *override.Bump
+	}
+	if override.TagTemplate != nil {
+		job.TagTemplate = *override.TagTemplate
+	}
+	if override.TagPrefix != nil {
+		job.TagPrefix = *override.TagPrefix
+	}
+	if override.ProtectedPattern != nil {
+		job.ProtectedPattern = *override.ProtectedPattern
+	}
+	if override.Branch != nil {
+		job.Branch = *override.Branch
+	}
+	if override.ReleaseNotes != nil {
+		job.ReleaseNotes = *override.ReleaseNotes
+	}
+	if override.ReleaseNotesTemplate != nil {
+		job.ReleaseNotesTemplate = *override.ReleaseNotesTemplate
+	}
+
+	return job
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+
+	"github.com/gobwas/glob"
+	"github.com/xanzy/go-gitlab"
+)
+
+// Job describes one tagging run: which projects to collect and which
+// flags to apply to each of them. A run with no --config file builds a
+// single Job directly from the CLI flags.
+type Job struct {
+	Project string
+	Group   string
+	Search  string
+	Include []string
+	Exclude []string
+
+	Forced           bool
+	Expired          string
+	Bump             string
+	TagTemplate      string
+	TagPrefix        string
+	ProtectedPattern string
+	Branch           string
+
+	ReleaseNotes         string
+	ReleaseNotesTemplate string
+}
+
+// jobFromOptions builds the single Job that a plain (configless) invocation
+// runs, directly from the top-level CLI flags.
+func jobFromOptions() Job {
+	return Job{
+		Project:              options.Project,
+		Group:                options.Group,
+		Search:               options.Search,
+		Forced:               options.Forced,
+		Expired:              options.Expired,
+		Bump:                 options.Bump,
+		TagTemplate:          options.TagTemplate,
+		TagPrefix:            options.TagPrefix,
+		ProtectedPattern:     options.ProtectedPattern,
+		Branch:               options.Branch,
+		ReleaseNotes:         options.ReleaseNotes,
+		ReleaseNotesTemplate: options.ReleaseNotesTemplate,
+	}
+}
+
+// collectJobProjects gathers the projects a Job targets and filters them by
+// its include/exclude glob patterns, matched against PathWithNamespace.
+func collectJobProjects(ctx context.Context, api *gitlab.Client, job Job) ([]*gitlab.Project, error) {
+	projects := make([]*gitlab.Project, 0)
+
+	if job.Project != "" {
+		project, _, err := api.Projects.GetProject(job.Project, &gitlab.GetProjectOptions{}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, project)
+	} else if job.Group != "" {
+		group, _, err := api.Groups.GetGroup(job.Group, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nested := range exploreSubGroups(ctx, api, group) {
+			projects = append(projects, exploreGroupProjects(ctx, api, nested, job.Search)...)
+		}
+	} else if job.Search != "" {
+		list, _, err := api.Projects.ListProjects(&gitlab.ListProjectsOptions{
+			ListOptions: gitlab.ListOptions{PerPage: PageOnceMax},
+			Simple:      gitlab.Bool(true),
+			Search:      gitlab.String(job.Search),
+		}, gitlab.WithContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+
+		projects = append(projects, list...)
+	}
+
+	include, err := compileGlobs(job.Include)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude, err := compileGlobs(job.Exclude)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterProjects(projects, include, exclude), nil
+}
+
+func compileGlobs(patterns []string) ([]glob.Glob, error) {
+	globs := make([]glob.Glob, 0, len(patterns))
+
+	for _, pattern := range patterns {
+		compiled, err := glob.Compile(pattern, '/')
+		if err != nil {
+			return nil, err
+		}
+
+		globs = append(globs, compiled)
+	}
+
+	return globs, nil
+}
+
+func filterProjects(projects []*gitlab.Project, include []glob.Glob, exclude []glob.Glob) []*gitlab.Project {
+	filtered := make([]*gitlab.Project, 0, len(projects))
+
+	for _, project := range projects {
+		if len(include) > 0 && !matchesAny(include, project.PathWithNamespace) {
+			continue
+		}
+
+		if matchesAny(exclude, project.PathWithNamespace) {
+			continue
+		}
+
+		filtered = append(filtered, project)
+	}
+
+	return filtered
+}
+
+func matchesAny(globs []glob.Glob, path string) bool {
+	for _, g := range globs {
+		if g.Match(path) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -1,15 +1,16 @@
 package main
 
 import (
-	"fmt"
+	"context"
+	"os"
+	"time"
+
 	"github.com/hashicorp/go-version"
 	"github.com/jessevdk/go-flags"
 	"github.com/karrick/tparse"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/xanzy/go-gitlab"
-	"os"
-	"time"
 )
 
 var options struct {
@@ -20,12 +21,28 @@ var options struct {
 	Search  string `long:"search" required:"false" description:"Gitlab projects search key"`
 	Forced  bool   `long:"force" required:"false" description:"Forced to re-tag (last tag or v1.0.0)"`
 	Expired string `long:"expired" required:"false" default:"now-1d" description:"Expired time to re-tag (diff with latest tag)"`
-	DryRun  bool   `long:"dry-run" required:"false"`
-	Debug   bool   `long:"debug" required:"false"`
+	Bump    string `long:"bump" required:"false" default:"patch" choice:"major" choice:"minor" choice:"patch" choice:"auto" description:"Version segment to bump, or infer it from Conventional Commits"`
+	Branch  string `long:"branch" required:"false" description:"Branch to tag, overriding each project's own default branch"`
+
+	TagTemplate      string `long:"tag-template" required:"false" description:"Go text/template for the version part of the tag name (fields: .Major .Minor .Patch .Pre .Meta .Project .Branch .Date), defaults to v{{.Major}}.{{.Minor}}.{{.Patch}}"`
+	TagPrefix        string `long:"tag-prefix" required:"false" description:"Go text/template rendered once per project and prepended to the tag name, e.g. '{{.Project}}/' for monorepo namespacing"`
+	ProtectedPattern string `long:"protected-pattern" required:"false" default:"v*" description:"Glob pattern used to (un)protect tags"`
+
+	Config      string `long:"config" required:"false" description:"YAML file defining multiple tagging jobs (targets) to run in one invocation, the flat flags above become their defaults"`
+	Concurrency int    `long:"concurrency" required:"false" default:"1" description:"Number of --config jobs to run in parallel"`
+
+	FailFast   bool   `long:"fail-fast" required:"false" description:"Abort the whole run on the first failing project, instead of continuing and reporting it"`
+	Report     string `long:"report" required:"false" default:"text" choice:"none" choice:"json" choice:"text" description:"Run report format, summarizing successes, skips and failures"`
+	ReportFile string `long:"report-file" required:"false" description:"File to write the run report to, defaults to stdout"`
+
+	ReleaseNotes         string `long:"release-notes" required:"false" default:"none" choice:"none" choice:"plain" choice:"markdown" description:"Generate release notes from the commits in the new tag, grouped as Features/Fixes/Other"`
+	ReleaseNotesTemplate string `long:"release-notes-template" required:"false" description:"Go text/template overriding the built-in plain/markdown release notes body (fields: .Project .Tag .Groups[].Type .Groups[].Commits)"`
+
+	DryRun bool `long:"dry-run" required:"false"`
+	Debug  bool `long:"debug" required:"false"`
 }
 
 const PageOnceMax = 999
-const ProtectedTagExpr = "v*"
 const DefaultBranchName = "master"
 
 func main() {
@@ -42,46 +59,43 @@ func main() {
 		panic(err)
 	}
 
-	var expired time.Time
-	if options.Expired != "" {
-		var err error
-		if expired, err = tparse.ParseNow(time.RFC3339, options.Expired); err != nil {
+	jobs := []Job{jobFromOptions()}
+	concurrency := options.Concurrency
+
+	if options.Config != "" {
+		config, err := loadConfig(options.Config)
+		if err != nil {
 			panic(err)
 		}
+
+		jobs = jobsFromConfig(config)
+		concurrency = resolveConcurrency(config, options.Concurrency)
 	}
 
-	projects := make([]*gitlab.Project, 0)
+	report := runJobs(gapi, jobs, concurrency, !options.DryRun, options.FailFast)
 
-	if options.Project != "" {
-		if project, _, err := gapi.Projects.GetProject(options.Project, &gitlab.GetProjectOptions{}); err != nil {
-			panic(err)
-		} else {
-			projects = append(projects, project)
-		}
-	} else if options.Group != "" {
-		if group, _, err := gapi.Groups.GetGroup(options.Group); err != nil {
-			panic(err)
-		} else {
-			for _, group := range exploreSubGroups(gapi, group) {
-				projects = append(projects, exploreGroupProjects(gapi, group, options.Search)...)
+	if options.Report != "none" {
+		out := os.Stdout
+		if options.ReportFile != "" {
+			file, err := os.Create(options.ReportFile)
+			if err != nil {
+				panic(err)
 			}
+			defer file.Close()
+			out = file
 		}
-	} else if options.Search != "" {
-		if list, _, err := gapi.Projects.ListProjects(&gitlab.ListProjectsOptions{
-			ListOptions: gitlab.ListOptions{PerPage: PageOnceMax},
-			Simple:      gitlab.Bool(true),
-			Search:      gitlab.String(options.Search),
-		}); err == nil {
-			projects = append(projects, list...)
+
+		if err := report.Write(options.Report, out); err != nil {
+			panic(err)
 		}
 	}
 
-	for _, project := range projects {
-		projectTagging(gapi, project, options.Forced, expired, !options.DryRun)
+	if report.Failed() {
+		os.Exit(1)
 	}
 }
 
-func exploreSubGroups(api *gitlab.Client, group *gitlab.Group) []*gitlab.Group {
+func exploreSubGroups(ctx context.Context, api *gitlab.Client, group *gitlab.Group) []*gitlab.Group {
 	log.Printf("Exploring sub-groups in %s", group.FullPath)
 
 	groups := []*gitlab.Group{group}
@@ -91,6 +105,7 @@ func exploreSubGroups(api *gitlab.Client, group *gitlab.Group) []*gitlab.Group {
 		&gitlab.ListSubgroupsOptions{
 			ListOptions: gitlab.ListOptions{PerPage: PageOnceMax},
 		},
+		gitlab.WithContext(ctx),
 	)
 
 	if err != nil {
@@ -98,13 +113,13 @@ func exploreSubGroups(api *gitlab.Client, group *gitlab.Group) []*gitlab.Group {
 	}
 
 	for _, nest := range list {
-		groups = append(groups, exploreSubGroups(api, nest)...)
+		groups = append(groups, exploreSubGroups(ctx, api, nest)...)
 	}
 
 	return groups
 }
 
-func exploreGroupProjects(api *gitlab.Client, group *gitlab.Group, search string) []*gitlab.Project {
+func exploreGroupProjects(ctx context.Context, api *gitlab.Client, group *gitlab.Group, search string) []*gitlab.Project {
 	log.Printf("Exploring projects in %s", group.FullPath)
 
 	options := &gitlab.ListGroupProjectsOptions{
@@ -116,104 +131,238 @@ func exploreGroupProjects(api *gitlab.Client, group *gitlab.Group, search string
 		options.Search = gitlab.String(search)
 	}
 
-	if list, _, err := api.Groups.ListGroupProjects(group.ID, options); err == nil {
+	if list, _, err := api.Groups.ListGroupProjects(group.ID, options, gitlab.WithContext(ctx)); err == nil {
 		return list
 	}
 
 	return nil
 }
 
-func projectTagging(api *gitlab.Client, project *gitlab.Project, forced bool, expired time.Time, do bool) {
+func projectTagging(ctx context.Context, api *gitlab.Client, project *gitlab.Project, job Job, do bool) Result {
 	log.Printf("[%s] Start to process tags", project.PathWithNamespace)
 
-	tags, _, err := api.Tags.ListTags(project.ID, &gitlab.ListTagsOptions{ListOptions: gitlab.ListOptions{PerPage: 1}})
+	result := Result{Project: project.PathWithNamespace, LastTag: "*NEVER*"}
+
+	branch := job.Branch
+	if branch == "" {
+		branch = project.DefaultBranch
+	}
+	if branch == "" {
+		branch = DefaultBranchName
+	}
+	log.Printf("[%s] Using branch %s", project.PathWithNamespace, branch)
+
+	var expired time.Time
+	if job.Expired != "" {
+		var err error
+		if expired, err = tparse.ParseNow(time.RFC3339, job.Expired); err != nil {
+			return failResult(result, err)
+		}
+	}
+
+	data := TagData{Project: project.PathWithNamespace, Branch: branch, Date: time.Now()}
+
+	prefix, err := renderTagPrefix(job.TagPrefix, data)
 	if err != nil {
-		panic(err)
+		return failResult(result, err)
 	}
 
-	last := "*NEVER*"
-	next := ""
-	if len(tags) > 0 {
-		latest := tags[0]
+	tags, _, err := api.Tags.ListTags(project.ID, &gitlab.ListTagsOptions{ListOptions: gitlab.ListOptions{PerPage: PageOnceMax}}, gitlab.WithContext(ctx))
+	if err != nil {
+		return failResult(result, err)
+	}
 
+	var latest *gitlab.Tag
+	var latestVersionName string
+	for _, tag := range tags {
+		if name, ok := unprefixedTagName(tag.Name, prefix); ok {
+			latest, latestVersionName = tag, name
+			break
+		}
+	}
+
+	next := ""
+	var rangeCommits []*gitlab.Commit
+	if latest != nil {
 		commits, _, err := api.Commits.ListCommits(project.ID, &gitlab.ListCommitsOptions{
 			ListOptions: gitlab.ListOptions{PerPage: 1},
-			RefName:     gitlab.String(DefaultBranchName),
-		})
+			RefName:     gitlab.String(branch),
+		}, gitlab.WithContext(ctx))
 		if err != nil {
-			panic(err)
+			return failResult(result, err)
+		}
+
+		if len(commits) == 0 {
+			log.Warn().Msgf("[%s] Branch %s has no commits -> skip", project.PathWithNamespace, branch)
+			return skipAtTag(result, latest.Name)
 		}
 
 		commit := commits[0]
-		if commit.ID == latest.Commit.ID {
+		if branchUnchangedSinceTag(commit, latest) {
 			log.Info().Msgf("[%s] No new commits submitted -> skip / latest is %s", project.PathWithNamespace, commit.ShortID)
-			return
+			return skipAtTag(result, latest.Name)
 		}
 
-		last = latest.Name
+		result.LastTag = latest.Name
 
-		if forced {
+		if job.Forced {
 			next = latest.Name
 		} else if !expired.IsZero() && latest.Commit.CommittedDate.Sub(expired) > 0 {
 			next = latest.Name
 		} else {
-			if ver, err := version.NewVersion(latest.Name); err != nil {
-				panic(err)
-			} else {
-				segs := ver.Segments()
-				next = fmt.Sprintf("v%d.%d.%d", segs[0], segs[1], segs[2]+1)
+			resolvedBump := job.Bump
+			if resolvedBump == BumpAuto {
+				var err error
+				if rangeCommits, err = commitsSinceTag(ctx, api, project, latest.Name, branch); err != nil {
+					return failResult(result, err)
+				}
+
+				if autoBumpRangeEmpty(rangeCommits) {
+					log.Info().Msgf("[%s] No commits between %s and %s -> skip", project.PathWithNamespace, latest.Name, branch)
+					result.Status = StatusSkipped
+					return result
+				}
+
+				resolvedBump = inferBump(rangeCommits)
+			}
+
+			ver, failed, ok := parseLatestVersion(result, latestVersionName)
+			if !ok {
+				return failed
+			}
+
+			data.Major, data.Minor, data.Patch, data.Pre, data.Meta = bumpSegments(ver, resolvedBump)
+			if next, err = renderTagName(job.TagPrefix, job.TagTemplate, data); err != nil {
+				return failResult(result, err)
 			}
 		}
 	} else {
-		next = "v1.0.0"
+		data.Major, data.Minor, data.Patch = 1, 0, 0
+		if next, err = renderTagName(job.TagPrefix, job.TagTemplate, data); err != nil {
+			return failResult(result, err)
+		}
 	}
 
-	created := "SKIP(dry-run)"
-	if do {
-		tagsUnprotected(api, project)
-		defer tagsProtected(api, project)
+	result.NextTag = next
 
-		if last == next {
-			if _, err := api.Tags.DeleteTag(project.ID, next); err != nil {
-				panic(err)
-			} else {
-				log.Warn().Msgf("[%s] Previous TAG:%s has been deleted", project.PathWithNamespace, last)
-			}
+	if latest != nil && rangeCommits == nil {
+		if rangeCommits, err = commitsSinceTag(ctx, api, project, latest.Name, branch); err != nil {
+			return failResult(result, err)
 		}
+	}
 
-		if tag, _, err := api.Tags.CreateTag(project.ID, &gitlab.CreateTagOptions{
-			TagName: gitlab.String(next),
-			Ref:     gitlab.String(DefaultBranchName),
-		}); err != nil {
-			panic(err)
-		} else {
-			created = fmt.Sprintf("DONE(%s:%s)", tag.Commit.ShortID, tag.Commit.Message)
+	if !do {
+		result.Status = StatusDryRun
+		for _, commit := range rangeCommits {
+			log.Info().Msgf("[%s] Would include %s %s", project.PathWithNamespace, commit.ShortID, commit.Title)
 		}
+		log.Info().Msgf("[%s] Tags will creating %s -> %s -> SKIP(dry-run)", project.PathWithNamespace, result.LastTag, next)
+		return result
 	}
 
-	log.Info().Msgf("[%s] Tags will creating %s -> %s -> %s", project.PathWithNamespace, last, next, created)
+	if err := tagsUnprotected(ctx, api, project, job.ProtectedPattern); err != nil {
+		return failResult(result, err)
+	}
+	defer func() {
+		if err := tagsProtected(ctx, api, project, job.ProtectedPattern); err != nil {
+			log.Error().Err(err).Msgf("[%s] Failed to re-protect tags", project.PathWithNamespace)
+		}
+	}()
+
+	if result.LastTag == next {
+		if _, err := api.Tags.DeleteTag(project.ID, next, gitlab.WithContext(ctx)); err != nil {
+			return failResult(result, err)
+		}
+
+		log.Warn().Msgf("[%s] Previous TAG:%s has been deleted", project.PathWithNamespace, result.LastTag)
+	}
+
+	createOptions := &gitlab.CreateTagOptions{
+		TagName: gitlab.String(next),
+		Ref:     gitlab.String(branch),
+	}
+
+	notes, err := renderReleaseNotes(job.ReleaseNotes, job.ReleaseNotesTemplate, project.PathWithNamespace, next, rangeCommits)
+	if err != nil {
+		return failResult(result, err)
+	}
+	if notes != "" {
+		createOptions.Message = gitlab.String(notes)
+	}
+
+	tag, _, err := api.Tags.CreateTag(project.ID, createOptions, gitlab.WithContext(ctx))
+	if err != nil {
+		return failResult(result, err)
+	}
+
+	result.Status = StatusCreated
+	log.Info().Msgf("[%s] Tags will creating %s -> %s -> DONE(%s:%s)", project.PathWithNamespace, result.LastTag, next, tag.Commit.ShortID, tag.Commit.Message)
+
+	return result
 }
 
-func tagsProtected(api *gitlab.Client, project *gitlab.Project) {
-	if protected, _, err := api.ProtectedTags.ProtectRepositoryTags(project.ID, &gitlab.ProtectRepositoryTagsOptions{
-		Name: gitlab.String(ProtectedTagExpr),
-	}); err != nil {
-		panic(err)
-	} else {
-		log.Printf("[%s] Repository tags protected in %s", project.PathWithNamespace, protected.Name)
+func failResult(result Result, err error) Result {
+	result.Status, result.Err = StatusFailed, err.Error()
+	return result
+}
+
+// skipAtTag marks result as skipped with lastTag left untouched by this run,
+// the outcome for both an empty branch and a branch that hasn't moved since
+// lastTag was cut.
+func skipAtTag(result Result, lastTag string) Result {
+	result.LastTag, result.Status = lastTag, StatusSkipped
+	return result
+}
+
+// branchUnchangedSinceTag reports whether branch's tip commit is the same
+// commit the latest tag already points at, i.e. there is nothing new to tag.
+func branchUnchangedSinceTag(tip *gitlab.Commit, latest *gitlab.Tag) bool {
+	return tip.ID == latest.Commit.ID
+}
+
+// autoBumpRangeEmpty reports whether a --bump auto run found no commits to
+// infer a bump from, in which case projectTagging skips the project.
+func autoBumpRangeEmpty(rangeCommits []*gitlab.Commit) bool {
+	return len(rangeCommits) == 0
+}
+
+// parseLatestVersion parses the latest tag's version part, returning ok=false
+// and a failResult wrapping the parse error when the tag name isn't valid
+// SemVer (e.g. a hand-made or foreign tag projectTagging doesn't own).
+func parseLatestVersion(result Result, latestVersionName string) (*version.Version, Result, bool) {
+	ver, err := version.NewVersion(latestVersionName)
+	if err != nil {
+		return nil, failResult(result, err), false
 	}
+
+	return ver, result, true
 }
 
-func tagsUnprotected(api *gitlab.Client, project *gitlab.Project) {
-	if protected, resp, err := api.ProtectedTags.GetProtectedTag(project.ID, ProtectedTagExpr); err != nil {
-		if resp.StatusCode != 404 {
-			panic(err)
-		}
-	} else {
-		if _, err := api.ProtectedTags.UnprotectRepositoryTags(project.ID, ProtectedTagExpr); err != nil {
-			panic(err)
-		} else {
-			log.Printf("[%s] Found and unprotected tag expr = %s", project.PathWithNamespace, protected.Name)
+func tagsProtected(ctx context.Context, api *gitlab.Client, project *gitlab.Project, protectedPattern string) error {
+	protected, _, err := api.ProtectedTags.ProtectRepositoryTags(project.ID, &gitlab.ProtectRepositoryTagsOptions{
+		Name: gitlab.String(protectedPattern),
+	}, gitlab.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+
+	log.Printf("[%s] Repository tags protected in %s", project.PathWithNamespace, protected.Name)
+	return nil
+}
+
+func tagsUnprotected(ctx context.Context, api *gitlab.Client, project *gitlab.Project, protectedPattern string) error {
+	protected, resp, err := api.ProtectedTags.GetProtectedTag(project.ID, protectedPattern, gitlab.WithContext(ctx))
+	if err != nil {
+		if resp != nil && resp.StatusCode == 404 {
+			return nil
 		}
+		return err
+	}
+
+	if _, err := api.ProtectedTags.UnprotectRepositoryTags(project.ID, protectedPattern, gitlab.WithContext(ctx)); err != nil {
+		return err
 	}
+
+	log.Printf("[%s] Found and unprotected tag expr = %s", project.PathWithNamespace, protected.Name)
+	return nil
 }
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"text/template"
+
+	"github.com/xanzy/go-gitlab"
+)
+
+const (
+	ReleaseNotesNone     = "none"
+	ReleaseNotesPlain    = "plain"
+	ReleaseNotesMarkdown = "markdown"
+)
+
+const defaultPlainReleaseNotesTemplate = `{{range .Groups}}{{.Type}}:
+{{range .Commits}}- {{.Title}} ({{.ShortID}})
+{{end}}
+{{end}}`
+
+const defaultMarkdownReleaseNotesTemplate = `{{range .Groups}}## {{.Type}}
+{{range .Commits}}- {{.Title}} (` + "`{{.ShortID}}`" + `)
+{{end}}
+{{end}}`
+
+// CommitGroup buckets commits by their Conventional Commits type, in the
+// order they should appear in release notes.
+type CommitGroup struct {
+	Type    string
+	Commits []*gitlab.Commit
+}
+
+// ReleaseNotesData is exposed to --release-notes-template.
+type ReleaseNotesData struct {
+	Project string
+	Tag     string
+	Groups  []CommitGroup
+}
+
+// groupCommits buckets commits into Features (feat), Fixes (fix/perf) and
+// Other, dropping empty groups, in that display order.
+func groupCommits(commits []*gitlab.Commit) []CommitGroup {
+	features := make([]*gitlab.Commit, 0)
+	fixes := make([]*gitlab.Commit, 0)
+	other := make([]*gitlab.Commit, 0)
+
+	for _, commit := range commits {
+		switch commitConventionalType(commit) {
+		case "feat":
+			features = append(features, commit)
+		case "fix", "perf":
+			fixes = append(fixes, commit)
+		default:
+			other = append(other, commit)
+		}
+	}
+
+	groups := make([]CommitGroup, 0, 3)
+	if len(features) > 0 {
+		groups = append(groups, CommitGroup{Type: "Features", Commits: features})
+	}
+	if len(fixes) > 0 {
+		groups = append(groups, CommitGroup{Type: "Fixes", Commits: fixes})
+	}
+	if len(other) > 0 {
+		groups = append(groups, CommitGroup{Type: "Other", Commits: other})
+	}
+
+	return groups
+}
+
+func commitConventionalType(commit *gitlab.Commit) string {
+	match := conventionalCommitExpr.FindStringSubmatch(commit.Title)
+	if match == nil {
+		return ""
+	}
+
+	return match[1]
+}
+
+// renderReleaseNotes renders the commits that would go into a tag as
+// release notes in the given format ("none" disables it), optionally using
+// a custom text/template instead of the built-in plain/markdown ones.
+func renderReleaseNotes(format string, tmplText string, project string, tag string, commits []*gitlab.Commit) (string, error) {
+	if format == "" || format == ReleaseNotesNone || len(commits) == 0 {
+		return "", nil
+	}
+
+	if tmplText == "" {
+		switch format {
+		case ReleaseNotesMarkdown:
+			tmplText = defaultMarkdownReleaseNotesTemplate
+		default:
+			tmplText = defaultPlainReleaseNotesTemplate
+		}
+	}
+
+	tmpl, err := template.New("release-notes").Parse(tmplText)
+	if err != nil {
+		return "", err
+	}
+
+	data := ReleaseNotesData{Project: project, Tag: tag, Groups: groupCommits(commits)}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Status is the outcome of processing a single project.
+type Status string
+
+const (
+	StatusCreated Status = "created"
+	StatusSkipped Status = "skipped"
+	StatusDryRun  Status = "dry-run"
+	StatusFailed  Status = "failed"
+)
+
+// Result is the outcome of tagging a single project, collected by a Report
+// instead of aborting the run.
+type Result struct {
+	Project string
+	LastTag string
+	NextTag string
+	Status  Status
+	Err     string
+}
+
+// Report aggregates the Results of a run so it can be summarized once
+// every job has finished, regardless of how many projects failed.
+type Report struct {
+	mu      sync.Mutex
+	Results []Result
+}
+
+func NewReport() *Report {
+	return &Report{}
+}
+
+func (r *Report) Add(result Result) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Results = append(r.Results, result)
+}
+
+// Failed reports whether any project in the run ended up with StatusFailed.
+func (r *Report) Failed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, result := range r.Results {
+		if result.Status == StatusFailed {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Write renders the report as "json" or "text" (the default).
+func (r *Report) Write(format string, out io.Writer) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch format {
+	case "json":
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(r.Results)
+	default:
+		var succeeded, skipped, failed int
+		for _, result := range r.Results {
+			switch result.Status {
+			case StatusFailed:
+				failed++
+			case StatusSkipped:
+				skipped++
+			default:
+				succeeded++
+			}
+
+			if _, err := fmt.Fprintf(out, "[%s] %s: %s -> %s", result.Project, result.Status, result.LastTag, result.NextTag); err != nil {
+				return err
+			}
+			if result.Err != "" {
+				if _, err := fmt.Fprintf(out, " (%s)", result.Err); err != nil {
+					return err
+				}
+			}
+			if _, err := fmt.Fprintln(out); err != nil {
+				return err
+			}
+		}
+
+		_, err := fmt.Fprintf(out, "Total: %d, succeeded: %d, skipped: %d, failed: %d\n", len(r.Results), succeeded, skipped, failed)
+		return err
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/xanzy/go-gitlab"
+)
+
+// runJobs collects and tags the projects for every Job, running up to
+// concurrency jobs at the same time, and returns a Report of every
+// project's outcome instead of aborting the run on the first error.
+// When failFast is set, the first failure cancels any jobs still pending.
+func runJobs(api *gitlab.Client, jobs []Job, concurrency int, do bool, failFast bool) *Report {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	report := NewReport()
+	semaphore := make(chan struct{}, concurrency)
+	var wg sync.WaitGroup
+
+	for _, job := range jobs {
+		select {
+		case <-ctx.Done():
+		default:
+			wg.Add(1)
+			semaphore <- struct{}{}
+
+			go func(job Job) {
+				defer wg.Done()
+				defer func() { <-semaphore }()
+				runJob(ctx, cancel, api, job, do, failFast, report)
+			}(job)
+		}
+	}
+
+	wg.Wait()
+
+	return report
+}
+
+func runJob(ctx context.Context, cancel context.CancelFunc, api *gitlab.Client, job Job, do bool, failFast bool, report *Report) {
+	select {
+	case <-ctx.Done():
+		return
+	default:
+	}
+
+	projects, err := collectJobProjects(ctx, api, job)
+	if err != nil {
+		log.Error().Err(err).Msgf("Failed to collect projects for job %+v", job)
+		report.Add(Result{Status: StatusFailed, Err: err.Error()})
+
+		if failFast {
+			cancel()
+		}
+		return
+	}
+
+	for _, project := range projects {
+		select {
+		case <-ctx.Done():
+			return
+		default:
+		}
+
+		result := projectTagging(ctx, api, project, job, do)
+		report.Add(result)
+
+		if result.Status == StatusFailed {
+			log.Error().Msgf("[%s] %s", result.Project, result.Err)
+
+			if failFast {
+				cancel()
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// DefaultTagTemplate reproduces the tool's original "v1.2.3" tag format.
+const DefaultTagTemplate = "v{{.Major}}.{{.Minor}}.{{.Patch}}{{if .Pre}}-{{.Pre}}{{end}}{{if .Meta}}+{{.Meta}}{{end}}"
+
+// TagData is the set of fields exposed to --tag-template and --tag-prefix.
+type TagData struct {
+	Major, Minor, Patch int
+	Pre, Meta           string
+	Project             string
+	Branch              string
+	Date                time.Time
+}
+
+// renderTagName renders the prefix and version templates and joins them into
+// a full tag name, e.g. "svc-foo/" + "v1.2.3" -> "svc-foo/v1.2.3".
+func renderTagName(prefixTmpl string, versionTmpl string, data TagData) (string, error) {
+	prefix, err := renderTemplate("tag-prefix", prefixTmpl, data)
+	if err != nil {
+		return "", err
+	}
+
+	if versionTmpl == "" {
+		versionTmpl = DefaultTagTemplate
+	}
+
+	version, err := renderTemplate("tag-template", versionTmpl, data)
+	if err != nil {
+		return "", err
+	}
+
+	return prefix + version, nil
+}
+
+// renderTagPrefix renders only the --tag-prefix template, used to filter the
+// tags that belong to this project/namespace before parsing them as versions.
+func renderTagPrefix(prefixTmpl string, data TagData) (string, error) {
+	return renderTemplate("tag-prefix", prefixTmpl, data)
+}
+
+func renderTemplate(name string, text string, data TagData) (string, error) {
+	if text == "" {
+		return "", nil
+	}
+
+	tmpl, err := template.New(name).Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+// unprefixedTagName strips prefix from name if name carries it, reporting
+// whether the tag belongs to the filtered namespace at all.
+func unprefixedTagName(name string, prefix string) (string, bool) {
+	if prefix == "" {
+		return name, true
+	}
+
+	if !strings.HasPrefix(name, prefix) {
+		return "", false
+	}
+
+	return strings.TrimPrefix(name, prefix), true
+}